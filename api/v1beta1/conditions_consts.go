@@ -0,0 +1,40 @@
+package v1beta1
+
+import clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+const (
+	// EtcdLearnerPromotingCondition documents that a learner member has joined the cluster and
+	// is being tracked for promotion to a voting member once it catches up with the leader.
+	EtcdLearnerPromotingCondition clusterv1.ConditionType = "EtcdLearnerPromoting"
+
+	// EtcdLearnerNotReadyReason is used when the leader reports the learner is not yet caught up,
+	// mirroring etcd's rpctypes.ErrLearnerNotReady.
+	EtcdLearnerNotReadyReason = "EtcdLearnerNotReady"
+
+	// EtcdTooManyLearnersReason is used when a promotion or a new learner join is deferred because
+	// a learner is already in flight, mirroring etcd's rpctypes.ErrTooManyLearners.
+	EtcdTooManyLearnersReason = "EtcdTooManyLearners"
+
+	// EtcdLearnerPromotionFailedReason is used when MemberPromote could not be completed after
+	// exhausting retries.
+	EtcdLearnerPromotionFailedReason = "EtcdLearnerPromotionFailed"
+
+	// EtcdClusterIDConsistentCondition documents that every endpoint's observed etcd cluster ID
+	// matches the one persisted on the EtcdadmCluster. It is false when one diverges, mirroring
+	// etcd's rpctypes.ErrClusterIdMismatch - usually because a rejoined machine came back with a
+	// reset data directory and formed (or joined) a different cluster under the same endpoint.
+	EtcdClusterIDConsistentCondition clusterv1.ConditionType = "EtcdClusterIDConsistent"
+
+	// EtcdClusterIDMismatchReason is the reason set alongside EtcdClusterIDConsistentCondition when
+	// it is false.
+	EtcdClusterIDMismatchReason = "EtcdClusterIDMismatch"
+
+	// EtcdConsistentCondition documents that HashChecker's last run found every endpoint reporting
+	// the same HashKV value at a common revision. It is false on divergence, indicating the
+	// members have diverged data.
+	EtcdConsistentCondition clusterv1.ConditionType = "EtcdConsistent"
+
+	// EtcdConsistencyViolationReason is the reason set alongside EtcdConsistentCondition when it is
+	// false.
+	EtcdConsistencyViolationReason = "EtcdConsistencyViolation"
+)
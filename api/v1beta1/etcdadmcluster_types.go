@@ -0,0 +1,109 @@
+package v1beta1
+
+import (
+	etcdbootstrapv1 "github.com/aws/etcdadm-bootstrap-provider/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// EtcdadmClusterFinalizer is the finalizer applied to EtcdadmCluster resources by its controller to
+	// ensure ordered cleanup of the etcd members and their owned Machines.
+	EtcdadmClusterFinalizer = "etcdadmcluster.etcdcluster.cluster.x-k8s.io"
+
+	// EtcdClusterIDOverrideAnnotation must be present for the controller to overwrite an already
+	// persisted Status.ClusterID. Without it, a mismatch is always treated as the newer endpoint
+	// being wrong rather than the recorded baseline being stale.
+	EtcdClusterIDOverrideAnnotation = "etcdcluster.cluster.x-k8s.io/override-cluster-id"
+
+	// HashCheckerAutoRemoveAnnotation opts an EtcdadmCluster into having HashChecker automatically
+	// queue the minority (outlier) member for removal when it detects a HashKV divergence. Without
+	// it, HashChecker only records EtcdConsistentCondition and emits an event.
+	HashCheckerAutoRemoveAnnotation = "etcdcluster.cluster.x-k8s.io/hashchecker-auto-remove"
+)
+
+// EtcdadmClusterSpec defines the desired state of EtcdadmCluster.
+type EtcdadmClusterSpec struct {
+	// Number of desired etcd members. Defaults to 3.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// EtcdadmConfigSpec is used to configure the etcdadm bootstrap provider for the Machines
+	// owned by this EtcdadmCluster.
+	EtcdadmConfigSpec etcdbootstrapv1.EtcdadmConfigSpec `json:"etcdadmConfigSpec"`
+
+	// InfrastructureTemplate is a reference to a provider-specific template that holds
+	// the details for provisioning infrastructure for each Machine of the EtcdadmCluster.
+	InfrastructureTemplate corev1.ObjectReference `json:"infrastructureTemplate"`
+}
+
+// EtcdadmClusterStatus defines the observed state of EtcdadmCluster.
+type EtcdadmClusterStatus struct {
+	// Endpoints is a comma separated list of reachable etcd client endpoints.
+	// +optional
+	Endpoints string `json:"endpoints,omitempty"`
+
+	// CreationComplete denotes that the first member of the etcd cluster has been created
+	// and initialized successfully.
+	// +optional
+	CreationComplete bool `json:"creationComplete,omitempty"`
+
+	// Ready denotes that the etcd cluster is ready to be used.
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// Replicas is the number of etcd members, voting and learner, currently observed.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// LearnerMembers lists the etcd endpoints that are currently joined as non-voting learners
+	// and have not yet been promoted to voting members.
+	// +optional
+	LearnerMembers []string `json:"learnerMembers,omitempty"`
+
+	// ClusterID is the etcd cluster ID observed on the first endpoint that passed a health check.
+	// It is persisted so a later ErrClusterIdMismatch can be detected even across controller
+	// restarts, and is only ever overwritten via the EtcdClusterIDOverrideAnnotation.
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// Conditions defines current service state of the EtcdadmCluster.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=etcdadmclusters,scope=Namespaced,categories=cluster-api
+// +kubebuilder:storageversion
+
+// EtcdadmCluster is the Schema for the etcdadmclusters API.
+type EtcdadmCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdadmClusterSpec   `json:"spec,omitempty"`
+	Status EtcdadmClusterStatus `json:"status,omitempty"`
+}
+
+func (c *EtcdadmCluster) GetConditions() clusterv1.Conditions {
+	return c.Status.Conditions
+}
+
+func (c *EtcdadmCluster) SetConditions(conditions clusterv1.Conditions) {
+	c.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// EtcdadmClusterList contains a list of EtcdadmCluster.
+type EtcdadmClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EtcdadmCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EtcdadmCluster{}, &EtcdadmClusterList{})
+}
@@ -0,0 +1,145 @@
+// Package etcd provides a thin wrapper around clientv3 for the operations the
+// EtcdadmCluster reconciler needs (membership, status and maintenance calls),
+// so that the controller package can depend on a small interface instead of
+// the full etcd client surface and can fake it out in tests.
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const defaultDialTimeout = 5 * time.Second
+
+// Member mirrors the subset of etcdserverpb.Member fields the reconciler cares about.
+type Member struct {
+	ID        uint64
+	Name      string
+	PeerURLs  []string
+	ClientURLs []string
+	IsLearner bool
+}
+
+// Status mirrors the subset of clientv3.StatusResponse fields the reconciler cares about.
+type Status struct {
+	Endpoint  string
+	Version   string
+	DbSize    int64
+	Leader    uint64
+	RaftIndex uint64
+	RaftTerm  uint64
+	ClusterID uint64
+	// Revision is the MVCC key-value store revision (Header.Revision), the unit HashKV's rev
+	// argument is expressed in. It is unrelated to RaftIndex, which advances on every raft log
+	// entry (including heartbeats) rather than only on key writes.
+	Revision int64
+}
+
+// WatchEvent reports a single notification from a Watch channel. Callers only need to know
+// whether the watch is still live, not the key/value that changed, so this deliberately drops the
+// rest of clientv3.WatchResponse.
+type WatchEvent struct {
+	// Canceled is true once the server has torn down the watch, e.g. because the watched
+	// revision was compacted or the connection was lost.
+	Canceled bool
+	// Err is the reason the watch was canceled, if any.
+	Err error
+}
+
+// Client is the interface the reconciler uses to talk to an etcd cluster. It is
+// satisfied by *clientWrapper and can be swapped out with a fake in tests.
+type Client interface {
+	MemberList(ctx context.Context) ([]*Member, error)
+	MemberPromote(ctx context.Context, id uint64) error
+	Status(ctx context.Context, endpoint string) (*Status, error)
+	SetEndpoints(endpoints ...string)
+	// Watch starts a watch on key and streams a WatchEvent for every event or cancellation the
+	// server sends. The channel closes when ctx is done or the server cancels the watch.
+	Watch(ctx context.Context, key string) <-chan WatchEvent
+	Close() error
+}
+
+type clientWrapper struct {
+	client *clientv3.Client
+}
+
+// NewClient returns a Client dialed against the given endpoints.
+func NewClient(endpoints []string, tlsConfig *tls.Config) (Client, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: defaultDialTimeout,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating etcd client for endpoints %v: %w", endpoints, err)
+	}
+	return &clientWrapper{client: cli}, nil
+}
+
+func (c *clientWrapper) MemberList(ctx context.Context) ([]*Member, error) {
+	resp, err := c.client.MemberList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing etcd members: %w", err)
+	}
+	members := make([]*Member, 0, len(resp.Members))
+	for _, m := range resp.Members {
+		members = append(members, &Member{
+			ID:         m.ID,
+			Name:       m.Name,
+			PeerURLs:   m.PeerURLs,
+			ClientURLs: m.ClientURLs,
+			IsLearner:  m.IsLearner,
+		})
+	}
+	return members, nil
+}
+
+func (c *clientWrapper) MemberPromote(ctx context.Context, id uint64) error {
+	_, err := c.client.MemberPromote(ctx, id)
+	return err
+}
+
+func (c *clientWrapper) Status(ctx context.Context, endpoint string) (*Status, error) {
+	resp, err := c.client.Status(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("getting etcd status for endpoint %s: %w", endpoint, err)
+	}
+	return &Status{
+		Endpoint:  endpoint,
+		Version:   resp.Version,
+		DbSize:    resp.DbSize,
+		Leader:    uint64(resp.Leader),
+		RaftIndex: resp.RaftIndex,
+		RaftTerm:  resp.RaftTerm,
+		ClusterID: resp.Header.GetClusterId(),
+		Revision:  resp.Header.GetRevision(),
+	}, nil
+}
+
+func (c *clientWrapper) SetEndpoints(endpoints ...string) {
+	c.client.SetEndpoints(endpoints...)
+}
+
+func (c *clientWrapper) Watch(ctx context.Context, key string) <-chan WatchEvent {
+	out := make(chan WatchEvent)
+	watchChan := c.client.Watch(ctx, key)
+	go func() {
+		defer close(out)
+		for resp := range watchChan {
+			select {
+			case out <- WatchEvent{Canceled: resp.Canceled, Err: resp.Err()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (c *clientWrapper) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,37 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+)
+
+// HashKVResult mirrors the subset of clientv3.HashKVResponse fields the HashChecker needs.
+type HashKVResult struct {
+	Hash            uint32
+	CompactRevision int64
+}
+
+// MaintenanceClient is the interface the HashChecker uses to compare data consistency across an
+// EtcdadmCluster's endpoints. It is satisfied by *clientWrapper and can be swapped out with a fake
+// in tests, the same way Client is.
+type MaintenanceClient interface {
+	Status(ctx context.Context, endpoint string) (*Status, error)
+	HashKV(ctx context.Context, endpoint string, rev int64) (*HashKVResult, error)
+}
+
+func (c *clientWrapper) HashKV(ctx context.Context, endpoint string, rev int64) (*HashKVResult, error) {
+	resp, err := c.client.HashKV(ctx, endpoint, rev)
+	if err != nil {
+		return nil, fmt.Errorf("hashing kv store at revision %d for endpoint %s: %w", rev, endpoint, err)
+	}
+	return &HashKVResult{Hash: resp.Hash, CompactRevision: resp.CompactRevision}, nil
+}
+
+// NewMaintenanceClient returns a MaintenanceClient dialed against the given endpoints.
+func NewMaintenanceClient(endpoints []string) (MaintenanceClient, error) {
+	cli, err := NewClient(endpoints, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cli.(*clientWrapper), nil
+}
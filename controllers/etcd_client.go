@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// etcdClientForCluster returns the long-lived etcd client for etcdadmCluster, dialing and caching
+// one on first use. The client's endpoint list is kept up to date by
+// updateHealthyEndpoints/endpointsProviderFor rather than by redialing here, so a partitioned
+// endpoint doesn't need to wait for this function to be called again with a fresh endpoint list.
+// Callers must not Close the returned client - it is owned by the reconciler.
+func (r *EtcdadmClusterReconciler) etcdClientForCluster(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster) (etcd.Client, error) {
+	r.etcdClientsMu.Lock()
+	defer r.etcdClientsMu.Unlock()
+
+	if r.etcdClients == nil {
+		r.etcdClients = make(map[types.UID]etcd.Client)
+	}
+
+	if cli, ok := r.etcdClients[etcdadmCluster.UID]; ok {
+		return cli, nil
+	}
+
+	endpoints := strings.Split(etcdadmCluster.Status.Endpoints, ",")
+	newClient := r.newEtcdClient
+	if newClient == nil {
+		newClient = func(endpoints []string) (etcd.Client, error) { return etcd.NewClient(endpoints, nil) }
+	}
+
+	cli, err := newClient(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	r.etcdClients[etcdadmCluster.UID] = cli
+	return cli, nil
+}
+
+// closeEtcdClientForCluster closes and forgets the cached etcd client and healthyEndpointsProvider
+// for uid, if any, so a deleted EtcdadmCluster doesn't leak its connection.
+func (r *EtcdadmClusterReconciler) closeEtcdClientForCluster(uid types.UID) {
+	r.etcdClientsMu.Lock()
+	defer r.etcdClientsMu.Unlock()
+
+	if cli, ok := r.etcdClients[uid]; ok {
+		if err := cli.Close(); err != nil {
+			r.Log.Error(err, "Error closing etcd client for deleted EtcdadmCluster")
+		}
+		delete(r.etcdClients, uid)
+	}
+	delete(r.endpointsProviders, uid)
+}
+
+// endpointsProviderFor returns the healthyEndpointsProvider tracking latency/consecutive-success
+// for etcdadmCluster, creating one on first use.
+func (r *EtcdadmClusterReconciler) endpointsProviderFor(etcdadmCluster *etcdv1.EtcdadmCluster) *healthyEndpointsProvider {
+	r.etcdClientsMu.Lock()
+	defer r.etcdClientsMu.Unlock()
+
+	if r.endpointsProviders == nil {
+		r.endpointsProviders = make(map[types.UID]*healthyEndpointsProvider)
+	}
+	provider, ok := r.endpointsProviders[etcdadmCluster.UID]
+	if !ok {
+		provider = newHealthyEndpointsProvider()
+		r.endpointsProviders[etcdadmCluster.UID] = provider
+	}
+	return provider
+}
+
+// updateHealthyEndpoints re-ranks etcdadmCluster's candidate endpoints through its
+// healthyEndpointsProvider and points the shared client at the result, demoting any endpoint that
+// has crossed endpointDemoteThreshold consecutive failures without waiting for
+// unhealthyMemberRemovalFrequency to remove its Machine.
+func (r *EtcdadmClusterReconciler) updateHealthyEndpoints(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster, candidates []string) error {
+	cli, err := r.etcdClientForCluster(ctx, etcdadmCluster)
+	if err != nil {
+		return err
+	}
+	provider := r.endpointsProviderFor(etcdadmCluster)
+
+	healthy := provider.HealthyEndpoints(candidates)
+	if len(healthy) == 0 {
+		// Every endpoint is demoted; leave the client pointed at the full candidate list rather
+		// than an empty one; there's nothing better to fail over to.
+		healthy = candidates
+	}
+	cli.SetEndpoints(healthy...)
+	return nil
+}
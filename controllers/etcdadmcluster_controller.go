@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EtcdadmClusterReconciler reconciles an EtcdadmCluster object, driving Machine creation/deletion
+// to match EtcdadmClusterSpec.Replicas and running a periodic health check loop against the
+// resulting etcd cluster.
+type EtcdadmClusterReconciler struct {
+	Client   client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// uncachedClient is used for reads that must bypass the controller-runtime cache, e.g. when
+	// listing Machines right after creating one so a stale cache entry can't be mistaken for
+	// "machine doesn't exist yet".
+	uncachedClient client.Client
+
+	// HealthCheckInterval is the period between runs of the etcd member health check loop.
+	// Defaults to 10 seconds; overridden in tests to keep them fast.
+	HealthCheckInterval time.Duration
+
+	// HashCheckInterval is the period between runs of the HashChecker consistency check. Defaults
+	// to 5 minutes; overridden in tests to keep them fast.
+	HashCheckInterval time.Duration
+
+	// WatchLoopUnhealthyTimeout is how long the watch health surveillance loop will wait without a
+	// watch event or a successful detectHealthyInterval probe before rotating off the current
+	// endpoint. Defaults to 60 seconds.
+	WatchLoopUnhealthyTimeout time.Duration
+
+	// DetectHealthyInterval is the period of the secondary probe the watch loop runs against its
+	// current endpoint independently of the watch, so a silent TCP blackhole - where the socket
+	// accepts but the server never responds or sends a watch event - is still caught. Defaults to
+	// 10 seconds.
+	DetectHealthyInterval time.Duration
+
+	// lastHashCheck records, per EtcdadmCluster UID, when HashChecker last ran so
+	// startHealthCheckLoop can gate it on HashCheckInterval without a second ticker.
+	lastHashCheck map[types.UID]time.Time
+
+	// watchLoopsMu guards watchLoopCancels.
+	watchLoopsMu sync.Mutex
+	// watchLoopCancels holds the cancel func for the running watch health surveillance loop per
+	// EtcdadmCluster UID, so startHealthCheckLoop can start at most one per cluster.
+	watchLoopCancels map[types.UID]context.CancelFunc
+
+	// newMaintenanceClient constructs the client HashChecker uses to call HashKV. Defaults to
+	// dialing a real clientv3.Client; tests in this package override it with a fake via
+	// SetMaintenanceClient.
+	newMaintenanceClient func(endpoints []string) (etcd.MaintenanceClient, error)
+
+	// isPortOpen is used to probe an etcd endpoint's reachability. Defaults to the package level
+	// IsPortOpen; overridable via SetIsPortOpen for tests.
+	isPortOpen func(ctx context.Context, address string) bool
+
+	// newEtcdClient constructs the etcd client used to talk to a cluster's endpoints. Defaults to
+	// dialing a real clientv3.Client; tests in this package override it directly with a fake.
+	newEtcdClient func(endpoints []string) (etcd.Client, error)
+
+	// etcdClientsMu guards etcdClients and endpointsProviders.
+	etcdClientsMu sync.Mutex
+	// etcdClients holds one long-lived etcd client per EtcdadmCluster UID, so health-check passes
+	// reuse a connection instead of dialing fresh on every tick.
+	etcdClients map[types.UID]etcd.Client
+	// endpointsProviders holds one healthyEndpointsProvider per EtcdadmCluster UID, tracking the
+	// latency/consecutive-success signal used to keep each client's endpoint list ranked.
+	endpointsProviders map[types.UID]*healthyEndpointsProvider
+}
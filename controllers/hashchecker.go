@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	// defaultHashCheckInterval is how often runHashCheck runs for a given EtcdadmCluster when
+	// HashCheckInterval is left unset.
+	defaultHashCheckInterval = 5 * time.Minute
+
+	// hashCheckMaxRetries bounds how many times a lagging member is re-hashed at the target
+	// revision before it's given up on for this pass.
+	hashCheckMaxRetries = 3
+
+	// hashCheckRetryBackoff is the wait between hashCheckMaxRetries attempts.
+	hashCheckRetryBackoff = time.Second
+)
+
+// SetMaintenanceClient overrides the function HashChecker uses to construct a MaintenanceClient.
+// Production code should never need this; it exists so tests can fake out the network.
+func (r *EtcdadmClusterReconciler) SetMaintenanceClient(f func(endpoints []string) (etcd.MaintenanceClient, error)) {
+	r.newMaintenanceClient = f
+}
+
+func (r *EtcdadmClusterReconciler) maintenanceClientFor(endpoints []string) (etcd.MaintenanceClient, error) {
+	if r.newMaintenanceClient != nil {
+		return r.newMaintenanceClient(endpoints)
+	}
+	return etcd.NewMaintenanceClient(endpoints)
+}
+
+// shouldRunHashCheck reports whether HashCheckInterval has elapsed since the last hash check for
+// etcdadmCluster, recording now as the new last-run time if so.
+func (r *EtcdadmClusterReconciler) shouldRunHashCheck(etcdadmCluster *etcdv1.EtcdadmCluster, now time.Time) bool {
+	interval := r.HashCheckInterval
+	if interval <= 0 {
+		interval = defaultHashCheckInterval
+	}
+	if r.lastHashCheck == nil {
+		r.lastHashCheck = make(map[types.UID]time.Time)
+	}
+	last, ok := r.lastHashCheck[etcdadmCluster.UID]
+	if ok && now.Sub(last) < interval {
+		return false
+	}
+	r.lastHashCheck[etcdadmCluster.UID] = now
+	return true
+}
+
+// runHashCheck compares the etcd data store hash across every endpoint in config at a common
+// revision, using the etcd Maintenance API. It records EtcdConsistentCondition and emits
+// an event on divergence, and - only when HashCheckerAutoRemoveAnnotation is set - queues the
+// minority endpoint(s) for removal via config.unhealthyMembersToRemove.
+//
+// The check is skipped entirely while a learner is in flight: a learner or a voting member still
+// catching up is expected to disagree with the leader's hash until it's caught up, which would
+// otherwise look identical to a real consistency violation.
+func (r *EtcdadmClusterReconciler) runHashCheck(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster, config *etcdadmClusterMemberHealthConfig) error {
+	if config.learnerEndpoint != "" {
+		return nil
+	}
+
+	endpoints := make([]string, 0, len(config.endpointToMachineMapper))
+	for endpoint := range config.endpointToMachineMapper {
+		endpoints = append(endpoints, endpoint)
+	}
+	if len(endpoints) < 2 {
+		return nil
+	}
+
+	cli, err := r.maintenanceClientFor(endpoints)
+	if err != nil {
+		return fmt.Errorf("creating maintenance client for hash check: %w", err)
+	}
+
+	var maxRevision int64
+	for _, endpoint := range endpoints {
+		status, err := cli.Status(ctx, endpoint)
+		if err != nil {
+			return fmt.Errorf("getting etcd status for endpoint %s: %w", endpoint, err)
+		}
+		if status.Revision > maxRevision {
+			maxRevision = status.Revision
+		}
+	}
+
+	hashesByEndpoint := make(map[string]uint32, len(endpoints))
+	for _, endpoint := range endpoints {
+		hash, err := r.hashKVWithRetry(ctx, cli, endpoint, maxRevision)
+		if err != nil {
+			return fmt.Errorf("hashing kv store for endpoint %s: %w", endpoint, err)
+		}
+		hashesByEndpoint[endpoint] = hash
+	}
+
+	outliers := outlierEndpoints(hashesByEndpoint)
+	if len(outliers) == 0 {
+		conditions.MarkTrue(etcdadmCluster, etcdv1.EtcdConsistentCondition)
+		return nil
+	}
+
+	msg := fmt.Sprintf("etcd members disagree on data hash at revision %d: %v", maxRevision, outliers)
+	conditions.MarkFalse(etcdadmCluster, etcdv1.EtcdConsistentCondition, etcdv1.EtcdConsistencyViolationReason, clusterv1.ConditionSeverityError, "%s", msg)
+	if r.Recorder != nil {
+		r.Recorder.Event(etcdadmCluster, "Warning", etcdv1.EtcdConsistencyViolationReason, msg)
+	}
+
+	if etcdadmCluster.Annotations[etcdv1.HashCheckerAutoRemoveAnnotation] != "true" {
+		return nil
+	}
+	for _, endpoint := range outliers {
+		if machine := config.endpointToMachineMapper[endpoint]; machine != nil {
+			config.unhealthyMembersToRemove[endpoint] = machine
+		}
+	}
+	return nil
+}
+
+// hashKVWithRetry re-hashes endpoint at rev up to hashCheckMaxRetries times, since a member that's
+// slightly behind will return an error until it reaches rev rather than a divergent hash.
+func (r *EtcdadmClusterReconciler) hashKVWithRetry(ctx context.Context, cli etcd.MaintenanceClient, endpoint string, rev int64) (uint32, error) {
+	var lastErr error
+	for attempt := 0; attempt < hashCheckMaxRetries; attempt++ {
+		result, err := cli.HashKV(ctx, endpoint, rev)
+		if err == nil {
+			return result.Hash, nil
+		}
+		lastErr = err
+		if attempt < hashCheckMaxRetries-1 {
+			time.Sleep(hashCheckRetryBackoff)
+		}
+	}
+	return 0, lastErr
+}
+
+// outlierEndpoints returns the endpoints reporting a hash that isn't the majority value. When
+// there is no majority (e.g. an even 1-1 split) every endpoint is returned as an outlier, since
+// there's no basis to prefer one over the other.
+func outlierEndpoints(hashesByEndpoint map[string]uint32) []string {
+	counts := make(map[uint32]int, len(hashesByEndpoint))
+	for _, hash := range hashesByEndpoint {
+		counts[hash]++
+	}
+	if len(counts) <= 1 {
+		return nil
+	}
+
+	var majorityHash uint32
+	majorityCount := 0
+	for hash, count := range counts {
+		if count > majorityCount {
+			majorityHash, majorityCount = hash, count
+		}
+	}
+	if majorityCount*2 <= len(hashesByEndpoint) {
+		outliers := make([]string, 0, len(hashesByEndpoint))
+		for endpoint := range hashesByEndpoint {
+			outliers = append(outliers, endpoint)
+		}
+		return outliers
+	}
+
+	var outliers []string
+	for endpoint, hash := range hashesByEndpoint {
+		if hash != majorityHash {
+			outliers = append(outliers, endpoint)
+		}
+	}
+	return outliers
+}
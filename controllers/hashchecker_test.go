@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"github.com/go-logr/zapr"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zaptest"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// fakeMaintenanceClient is a minimal etcd.MaintenanceClient double for HashChecker tests.
+type fakeMaintenanceClient struct {
+	statusByEndpt map[string]*etcd.Status
+	hashByEndpt   map[string]uint32
+	hashErrCount  map[string]int
+	// hashRevByEndpt records the rev runHashCheck actually passed to HashKV for each endpoint, so
+	// tests can assert it's the MVCC revision and not, say, a raft index.
+	hashRevByEndpt map[string]int64
+}
+
+func (f *fakeMaintenanceClient) Status(_ context.Context, endpoint string) (*etcd.Status, error) {
+	s, ok := f.statusByEndpt[endpoint]
+	if !ok {
+		return nil, fmt.Errorf("no fake status configured for endpoint %s", endpoint)
+	}
+	return s, nil
+}
+
+func (f *fakeMaintenanceClient) HashKV(_ context.Context, endpoint string, rev int64) (*etcd.HashKVResult, error) {
+	if f.hashRevByEndpt == nil {
+		f.hashRevByEndpt = make(map[string]int64)
+	}
+	f.hashRevByEndpt[endpoint] = rev
+	if remaining := f.hashErrCount[endpoint]; remaining > 0 {
+		f.hashErrCount[endpoint]--
+		return nil, fmt.Errorf("etcdserver: mvcc: required revision is a future revision")
+	}
+	hash, ok := f.hashByEndpt[endpoint]
+	if !ok {
+		return nil, fmt.Errorf("no fake hash configured for endpoint %s", endpoint)
+	}
+	return &etcd.HashKVResult{Hash: hash}, nil
+}
+
+func newHashCheckFixtures(t *testing.T) (*EtcdadmClusterReconciler, *etcdv1.EtcdadmCluster, *fakeMaintenanceClient) {
+	t.Helper()
+	fakeClient := &fakeMaintenanceClient{
+		// RaftIndex is deliberately set far ahead of Revision, as it is in a real cluster where
+		// heartbeats advance the raft index without touching the MVCC revision - a regression that
+		// fed RaftIndex into HashKV's rev argument would hash at 9999 instead of 100.
+		statusByEndpt: map[string]*etcd.Status{
+			fakeLeaderEndpoint:  {RaftIndex: 9999, Revision: 100},
+			fakeLearnerEndpoint: {RaftIndex: 9999, Revision: 100},
+		},
+		hashErrCount: map[string]int{},
+	}
+
+	r := &EtcdadmClusterReconciler{Log: zapr.NewLogger(zaptest.NewLogger(t))}
+	r.SetMaintenanceClient(func(_ []string) (etcd.MaintenanceClient, error) { return fakeClient, nil })
+
+	cluster := newClusterWithExternalEtcd()
+	etcdadmCluster := newEtcdadmCluster(cluster)
+
+	return r, etcdadmCluster, fakeClient
+}
+
+func newTestHealthConfig() *etcdadmClusterMemberHealthConfig {
+	return &etcdadmClusterMemberHealthConfig{
+		unhealthyMembersFrequency: make(map[string]int),
+		unhealthyMembersToRemove:  make(map[string]*clusterv1.Machine),
+		endpointToMachineMapper: map[string]*clusterv1.Machine{
+			fakeLeaderEndpoint:  {},
+			fakeLearnerEndpoint: {},
+		},
+	}
+}
+
+func TestRunHashCheckPassesWhenHashesMatch(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newHashCheckFixtures(t)
+	fakeClient.hashByEndpt = map[string]uint32{fakeLeaderEndpoint: 42, fakeLearnerEndpoint: 42}
+	config := newTestHealthConfig()
+
+	err := r.runHashCheck(context.Background(), etcdadmCluster, config)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.IsTrue(etcdadmCluster, etcdv1.EtcdConsistentCondition)).To(BeTrue())
+	g.Expect(config.unhealthyMembersToRemove).To(BeEmpty())
+	g.Expect(fakeClient.hashRevByEndpt[fakeLeaderEndpoint]).To(Equal(int64(100)), "should hash at the MVCC revision, not the raft index")
+}
+
+func TestRunHashCheckDetectsDivergenceWithoutAutoRemove(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newHashCheckFixtures(t)
+	fakeClient.hashByEndpt = map[string]uint32{fakeLeaderEndpoint: 42, fakeLearnerEndpoint: 99}
+	config := newTestHealthConfig()
+
+	err := r.runHashCheck(context.Background(), etcdadmCluster, config)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.IsFalse(etcdadmCluster, etcdv1.EtcdConsistentCondition)).To(BeTrue())
+	g.Expect(config.unhealthyMembersToRemove).To(BeEmpty(), "without the opt-in annotation the outlier should only be flagged, not removed")
+}
+
+func TestRunHashCheckRemovesOutlierWhenAnnotated(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newHashCheckFixtures(t)
+	etcdadmCluster.Annotations = map[string]string{etcdv1.HashCheckerAutoRemoveAnnotation: "true"}
+	fakeClient.hashByEndpt = map[string]uint32{fakeLeaderEndpoint: 42, fakeLearnerEndpoint: 99}
+	config := newTestHealthConfig()
+
+	err := r.runHashCheck(context.Background(), etcdadmCluster, config)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(config.unhealthyMembersToRemove).To(HaveKey(fakeLearnerEndpoint))
+}
+
+func TestRunHashCheckSkipsWhileLearnerInFlight(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, _ := newHashCheckFixtures(t)
+	config := newTestHealthConfig()
+	config.learnerEndpoint = fakeLearnerEndpoint
+
+	err := r.runHashCheck(context.Background(), etcdadmCluster, config)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.Has(etcdadmCluster, etcdv1.EtcdConsistentCondition)).To(BeFalse())
+}
+
+func TestRunHashCheckRetriesLaggingMember(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newHashCheckFixtures(t)
+	fakeClient.hashByEndpt = map[string]uint32{fakeLeaderEndpoint: 42, fakeLearnerEndpoint: 42}
+	fakeClient.hashErrCount[fakeLearnerEndpoint] = hashCheckMaxRetries - 1
+	config := newTestHealthConfig()
+
+	err := r.runHashCheck(context.Background(), etcdadmCluster, config)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(conditions.IsTrue(etcdadmCluster, etcdv1.EtcdConsistentCondition)).To(BeTrue())
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// endpointDemoteThreshold is the number of consecutive failed probes after which an endpoint is
+// dropped from the healthy set, independent of unhealthyMemberRemovalFrequency which governs when
+// its Machine is actually removed.
+const endpointDemoteThreshold = 3
+
+// endpointStats tracks the rolling health signal healthyEndpointsProvider ranks endpoints by.
+type endpointStats struct {
+	p99LatencySeconds  float64
+	consecutiveSuccess int
+	consecutiveFailure int
+}
+
+// healthyEndpointsProvider ranks an EtcdadmCluster's etcd endpoints by recent latency and
+// consecutive-success count, so the shared clientv3.Client can be pointed away from a partitioned
+// or dead endpoint immediately instead of waiting for unhealthyMemberRemovalFrequency to remove
+// its Machine.
+type healthyEndpointsProvider struct {
+	mu    sync.Mutex
+	stats map[string]*endpointStats
+}
+
+func newHealthyEndpointsProvider() *healthyEndpointsProvider {
+	return &healthyEndpointsProvider{stats: make(map[string]*endpointStats)}
+}
+
+// RecordSuccess records a successful probe of endpoint that took latency to complete.
+func (p *healthyEndpointsProvider) RecordSuccess(endpoint string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.statsFor(endpoint)
+	s.consecutiveSuccess++
+	s.consecutiveFailure = 0
+	// A simple exponentially-weighted moving average is enough to rank endpoints; this isn't
+	// trying to be a real p99 estimator, just a signal that's stickier than the last sample.
+	const alpha = 0.2
+	observed := latency.Seconds()
+	if s.p99LatencySeconds == 0 {
+		s.p99LatencySeconds = observed
+	} else {
+		s.p99LatencySeconds = alpha*observed + (1-alpha)*s.p99LatencySeconds
+	}
+
+	endpointLatencySeconds.WithLabelValues(endpoint).Observe(observed)
+}
+
+// RecordFailure records a failed probe of endpoint.
+func (p *healthyEndpointsProvider) RecordFailure(endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.statsFor(endpoint)
+	s.consecutiveFailure++
+	s.consecutiveSuccess = 0
+}
+
+func (p *healthyEndpointsProvider) statsFor(endpoint string) *endpointStats {
+	s, ok := p.stats[endpoint]
+	if !ok {
+		s = &endpointStats{}
+		p.stats[endpoint] = s
+	}
+	return s
+}
+
+// HealthyEndpoints returns the endpoints that haven't crossed endpointDemoteThreshold consecutive
+// failures, ordered from lowest to highest moving-average latency as recorded by RecordSuccess
+// (not a true p99), with ties broken by the higher consecutive-success count. Endpoints with no
+// recorded stats yet (never probed) are treated as healthy and sorted last.
+func (p *healthyEndpointsProvider) HealthyEndpoints(candidates []string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	healthy := make([]string, 0, len(candidates))
+	for _, endpoint := range candidates {
+		if s, ok := p.stats[endpoint]; ok && s.consecutiveFailure >= endpointDemoteThreshold {
+			continue
+		}
+		healthy = append(healthy, endpoint)
+	}
+
+	sort.SliceStable(healthy, func(i, j int) bool {
+		si, iok := p.stats[healthy[i]]
+		sj, jok := p.stats[healthy[j]]
+		if !iok {
+			return false
+		}
+		if !jok {
+			return true
+		}
+		if si.p99LatencySeconds != sj.p99LatencySeconds {
+			return si.p99LatencySeconds < sj.p99LatencySeconds
+		}
+		return si.consecutiveSuccess > sj.consecutiveSuccess
+	})
+
+	return healthy
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHealthyEndpointsProviderRanksByLatency(t *testing.T) {
+	g := NewWithT(t)
+	p := newHealthyEndpointsProvider()
+
+	p.RecordSuccess("slow:2379", 200*time.Millisecond)
+	p.RecordSuccess("fast:2379", 10*time.Millisecond)
+
+	g.Expect(p.HealthyEndpoints([]string{"slow:2379", "fast:2379"})).To(Equal([]string{"fast:2379", "slow:2379"}))
+}
+
+func TestHealthyEndpointsProviderDemotesAfterConsecutiveFailures(t *testing.T) {
+	g := NewWithT(t)
+	p := newHealthyEndpointsProvider()
+
+	p.RecordSuccess("a:2379", time.Millisecond)
+	p.RecordSuccess("b:2379", time.Millisecond)
+	for i := 0; i < endpointDemoteThreshold; i++ {
+		p.RecordFailure("b:2379")
+	}
+
+	g.Expect(p.HealthyEndpoints([]string{"a:2379", "b:2379"})).To(Equal([]string{"a:2379"}))
+}
+
+func TestHealthyEndpointsProviderRecoversAfterSuccessResetsFailureStreak(t *testing.T) {
+	g := NewWithT(t)
+	p := newHealthyEndpointsProvider()
+
+	for i := 0; i < endpointDemoteThreshold-1; i++ {
+		p.RecordFailure("a:2379")
+	}
+	p.RecordSuccess("a:2379", time.Millisecond)
+	p.RecordFailure("a:2379")
+
+	g.Expect(p.HealthyEndpoints([]string{"a:2379"})).To(Equal([]string{"a:2379"}), "a single failure after a success should not immediately demote the endpoint")
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var endpointLatencySeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "etcdadm_controller_endpoint_latency_seconds",
+		Help:    "Observed latency of etcd health probes against a single endpoint.",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"endpoint"},
+)
+
+var watchReconnectTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "etcdadm_controller_watch_reconnect_total",
+		Help: "Count of times the watch health surveillance loop rotated away from an endpoint after a cancellation or an unhealthy timeout.",
+	},
+	[]string{"cluster"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(endpointLatencySeconds, watchReconnectTotal)
+}
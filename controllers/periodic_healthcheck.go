@@ -0,0 +1,525 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultHealthCheckInterval is how often startHealthCheckLoop runs a pass over every
+	// EtcdadmCluster when HealthCheckInterval is left unset.
+	defaultHealthCheckInterval = 10 * time.Second
+
+	// unhealthyMemberFrequencyThreshold is the number of consecutive failed health checks an
+	// endpoint must accumulate before it is queued for removal.
+	unhealthyMemberFrequencyThreshold = 3
+
+	// maxLearnerRaftIndexLag is the largest difference between a learner's raft index and the
+	// leader's at which the learner is considered caught up and eligible for promotion. It is a
+	// fixed value; no reconciler field overrides it.
+	maxLearnerRaftIndexLag = 100
+
+	// learnerPromotionMaxAttempts bounds the number of failed MemberPromote calls (such as
+	// ErrLearnerNotReady) across health check passes for a single learner, so a stuck learner can't block the reconcile loop
+	// forever; the HealthCheckInterval between passes already provides the retry backoff.
+	learnerPromotionMaxAttempts = 3
+)
+
+// IsPortOpen reports whether a TCP connection can be established to address. It is a package
+// level default so it can be overridden wholesale in tests via SetIsPortOpen.
+func IsPortOpen(ctx context.Context, address string) bool {
+	d := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// SetIsPortOpen overrides the function the reconciler uses to probe an endpoint's reachability.
+// Production code should never need this; it exists so tests can fake out the network.
+func (r *EtcdadmClusterReconciler) SetIsPortOpen(f func(ctx context.Context, address string) bool) {
+	r.isPortOpen = f
+}
+
+func (r *EtcdadmClusterReconciler) isPortOpenOrDefault() func(ctx context.Context, address string) bool {
+	if r.isPortOpen != nil {
+		return r.isPortOpen
+	}
+	return IsPortOpen
+}
+
+// etcdadmClusterMemberHealthConfig tracks per-member health-check bookkeeping for a single
+// EtcdadmCluster between reconciles, keyed by the EtcdadmCluster's UID in startHealthCheckLoop.
+type etcdadmClusterMemberHealthConfig struct {
+	// unhealthyMembersFrequency counts consecutive failed health checks per endpoint.
+	unhealthyMembersFrequency map[string]int
+	// unhealthyMembersToRemove holds Machines whose etcd member has crossed the unhealthy
+	// threshold and is queued for removal.
+	unhealthyMembersToRemove map[string]*clusterv1.Machine
+	// endpointToMachineMapper maps an etcd client endpoint to the Machine that owns it, or nil if
+	// no owning Machine could be found.
+	endpointToMachineMapper map[string]*clusterv1.Machine
+	// learnerMachineName is the name of the Machine created for the in-flight learner, set as soon
+	// as addLearnerMachine creates it. Only one learner is tracked at a time to avoid etcd's
+	// ErrTooManyLearners. It is the guard against a second scale-up while resolveLearnerEndpoint is
+	// still waiting for the Machine's infrastructure to report an address.
+	learnerMachineName string
+	// learnerEndpoint is the etcd client endpoint of the in-flight learner named by
+	// learnerMachineName, populated by resolveLearnerEndpoint once its Machine has a real reported
+	// address. Empty while a learner Machine exists but hasn't been provisioned yet.
+	learnerEndpoint string
+	// learnerPromotionAttempts counts consecutive failed MemberPromote calls for learnerEndpoint.
+	learnerPromotionAttempts int
+
+	cluster       *clusterv1.Cluster
+	ownedMachines collections.Machines
+}
+
+// startHealthCheckLoop runs periodicEtcdMembersHealthCheck for every EtcdadmCluster on a ticker,
+// until done is closed.
+func (r *EtcdadmClusterReconciler) startHealthCheckLoop(ctx context.Context, done <-chan struct{}) {
+	interval := r.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	etcdadmClusterMapper := make(map[types.UID]etcdadmClusterMemberHealthConfig)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			var etcdadmClusters etcdv1.EtcdadmClusterList
+			if err := r.Client.List(ctx, &etcdadmClusters); err != nil {
+				r.Log.Error(err, "Error listing EtcdadmClusters during health check")
+				continue
+			}
+
+			r.cleanupDeletedClusters(etcdadmClusterMapper, etcdadmClusters.Items)
+
+			for i := range etcdadmClusters.Items {
+				etcdadmCluster := &etcdadmClusters.Items[i]
+				log := r.Log.WithValues("etcdadmCluster", etcdadmCluster.Name, "namespace", etcdadmCluster.Namespace)
+
+				// A paused cluster, one that hasn't finished creating its first member yet, or one
+				// whose owner Cluster can't be resolved all have nothing useful to health-check yet;
+				// they share this message rather than three near-identical ones.
+				annotations := etcdadmCluster.GetAnnotations()
+				isPaused := annotations[clusterv1.PausedAnnotation] == "true"
+				if isPaused || !etcdadmCluster.Status.CreationComplete {
+					log.Info("HealthCheck paused for EtcdadmCluster, skipping")
+					continue
+				}
+
+				ownerCluster, err := r.getOwnerCluster(ctx, etcdadmCluster)
+				if err != nil {
+					log.Info("HealthCheck paused for EtcdadmCluster, skipping")
+					continue
+				}
+
+				r.ensureWatchLoop(ctx, etcdadmCluster)
+
+				if err := r.periodicEtcdMembersHealthCheck(ctx, ownerCluster, etcdadmCluster, etcdadmClusterMapper); err != nil {
+					log.Error(err, "Error running periodic etcd members health check")
+				}
+
+				if r.shouldRunHashCheck(etcdadmCluster, time.Now()) {
+					config := etcdadmClusterMapper[etcdadmCluster.UID]
+					if err := r.runHashCheck(ctx, etcdadmCluster, &config); err != nil {
+						log.Error(err, "Error running etcd consistency hash check")
+					}
+					etcdadmClusterMapper[etcdadmCluster.UID] = config
+				}
+
+				// periodicEtcdMembersHealthCheck and runHashCheck only mutate etcdadmCluster in memory;
+				// persist the ClusterID/LearnerMembers/condition changes from this pass so they survive
+				// the fresh List on the next tick and are visible to anything watching the object.
+				if err := r.Client.Status().Update(ctx, etcdadmCluster); err != nil {
+					log.Error(err, "Error persisting EtcdadmCluster status after health check pass")
+				}
+			}
+		}
+	}
+}
+
+// cleanupDeletedClusters tears down the per-UID state startHealthCheckLoop has accumulated for any
+// EtcdadmCluster that no longer appears in live - its watch health surveillance loop, cached etcd
+// client, and bookkeeping in etcdadmClusterMapper/lastHashCheck - so a deleted EtcdadmCluster
+// doesn't leak a goroutine and an open connection forever.
+func (r *EtcdadmClusterReconciler) cleanupDeletedClusters(etcdadmClusterMapper map[types.UID]etcdadmClusterMemberHealthConfig, live []etcdv1.EtcdadmCluster) {
+	liveUIDs := make(map[types.UID]bool, len(live))
+	for _, etcdadmCluster := range live {
+		liveUIDs[etcdadmCluster.UID] = true
+	}
+
+	tracked := make(map[types.UID]bool)
+	for uid := range etcdadmClusterMapper {
+		tracked[uid] = true
+	}
+	for uid := range r.lastHashCheck {
+		tracked[uid] = true
+	}
+	r.watchLoopsMu.Lock()
+	for uid := range r.watchLoopCancels {
+		tracked[uid] = true
+	}
+	r.watchLoopsMu.Unlock()
+	r.etcdClientsMu.Lock()
+	for uid := range r.etcdClients {
+		tracked[uid] = true
+	}
+	for uid := range r.endpointsProviders {
+		tracked[uid] = true
+	}
+	r.etcdClientsMu.Unlock()
+
+	for uid := range tracked {
+		if liveUIDs[uid] {
+			continue
+		}
+		delete(etcdadmClusterMapper, uid)
+		delete(r.lastHashCheck, uid)
+		r.stopWatchLoop(uid)
+		r.closeEtcdClientForCluster(uid)
+	}
+}
+
+// uncachedClientOrDefault returns the reconciler's cache-bypassing client, falling back to the
+// regular cached Client if one was never wired up (e.g. in tests that construct the reconciler
+// directly rather than through the manager).
+func (r *EtcdadmClusterReconciler) uncachedClientOrDefault() client.Client {
+	if r.uncachedClient != nil {
+		return r.uncachedClient
+	}
+	return r.Client
+}
+
+// discoverEtcdMembers lists the Machines owned by etcdadmCluster - via uncachedClientOrDefault, so
+// a Machine created earlier in this same pass is never missed because of a stale cache read - and
+// (re)populates config.ownedMachines and config.endpointToMachineMapper from their reported
+// addresses. A Machine whose infrastructure hasn't reported an address yet is tracked in
+// ownedMachines but has no entry in endpointToMachineMapper until it does.
+func (r *EtcdadmClusterReconciler) discoverEtcdMembers(ctx context.Context, cluster *clusterv1.Cluster, etcdadmCluster *etcdv1.EtcdadmCluster, config *etcdadmClusterMemberHealthConfig) error {
+	var machineList clusterv1.MachineList
+	if err := r.uncachedClientOrDefault().List(ctx, &machineList,
+		client.InNamespace(etcdadmCluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: cluster.Name},
+	); err != nil {
+		return fmt.Errorf("listing Machines for EtcdadmCluster %s/%s: %w", etcdadmCluster.Namespace, etcdadmCluster.Name, err)
+	}
+
+	ownedMachines := collections.FromMachineList(&machineList).Filter(collections.OwnedMachines(etcdadmCluster))
+	config.ownedMachines = ownedMachines
+
+	endpointToMachineMapper := make(map[string]*clusterv1.Machine, len(ownedMachines))
+	for _, machine := range ownedMachines {
+		if endpoint := machineEtcdEndpoint(machine); endpoint != "" {
+			endpointToMachineMapper[endpoint] = machine
+		}
+	}
+	config.endpointToMachineMapper = endpointToMachineMapper
+
+	return nil
+}
+
+// machineEtcdEndpoint returns the etcd client endpoint for machine, taken from its first reported
+// external IP address, or "" if its infrastructure hasn't reported one yet.
+func machineEtcdEndpoint(machine *clusterv1.Machine) string {
+	for _, addr := range machine.Status.Addresses {
+		if addr.Type == clusterv1.MachineExternalIP {
+			return addr.Address
+		}
+	}
+	return ""
+}
+
+// resolveLearnerEndpoint fills in config.learnerEndpoint from the real reported address of
+// config.learnerMachineName, the same way every other endpoint in config is derived, once its
+// infrastructure has provisioned it. It's a no-op if there's no learner in flight, its endpoint is
+// already resolved, or its Machine hasn't reported an address yet.
+func resolveLearnerEndpoint(etcdadmCluster *etcdv1.EtcdadmCluster, config *etcdadmClusterMemberHealthConfig) {
+	if config.learnerMachineName == "" || config.learnerEndpoint != "" {
+		return
+	}
+	for _, machine := range config.ownedMachines {
+		if machine.Name != config.learnerMachineName {
+			continue
+		}
+		if endpoint := machineEtcdEndpoint(machine); endpoint != "" {
+			config.learnerEndpoint = endpoint
+			etcdadmCluster.Status.LearnerMembers = append(etcdadmCluster.Status.LearnerMembers, endpoint)
+		}
+		return
+	}
+}
+
+func (r *EtcdadmClusterReconciler) getOwnerCluster(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster) (*clusterv1.Cluster, error) {
+	for _, ref := range etcdadmCluster.OwnerReferences {
+		if ref.Kind == "Cluster" {
+			var cluster clusterv1.Cluster
+			if err := r.Client.Get(ctx, client.ObjectKey{Namespace: etcdadmCluster.Namespace, Name: ref.Name}, &cluster); err != nil {
+				return nil, err
+			}
+			return &cluster, nil
+		}
+	}
+	return nil, fmt.Errorf("EtcdadmCluster %s/%s has no owner Cluster", etcdadmCluster.Namespace, etcdadmCluster.Name)
+}
+
+// periodicEtcdMembersHealthCheck probes every known etcd endpoint, accumulates unhealthy state in
+// etcdadmClusterMapper, and drives any in-flight learner through the join->catch-up->promote
+// workflow.
+func (r *EtcdadmClusterReconciler) periodicEtcdMembersHealthCheck(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	etcdadmCluster *etcdv1.EtcdadmCluster,
+	etcdadmClusterMapper map[types.UID]etcdadmClusterMemberHealthConfig,
+) error {
+	log := r.Log.WithValues("etcdadmCluster", etcdadmCluster.Name, "namespace", etcdadmCluster.Namespace)
+
+	config, ok := etcdadmClusterMapper[etcdadmCluster.UID]
+	if !ok {
+		config = etcdadmClusterMemberHealthConfig{
+			unhealthyMembersFrequency: make(map[string]int),
+			unhealthyMembersToRemove:  make(map[string]*clusterv1.Machine),
+			endpointToMachineMapper:   make(map[string]*clusterv1.Machine),
+			cluster:                   cluster,
+		}
+	}
+
+	if err := r.discoverEtcdMembers(ctx, cluster, etcdadmCluster, &config); err != nil {
+		log.Error(err, "Error discovering etcd member Machines")
+	}
+	resolveLearnerEndpoint(etcdadmCluster, &config)
+
+	isPortOpen := r.isPortOpenOrDefault()
+	provider := r.endpointsProviderFor(etcdadmCluster)
+	candidates := make([]string, 0, len(config.endpointToMachineMapper))
+	clusterIDMismatchSeen := false
+
+	for endpoint, machine := range config.endpointToMachineMapper {
+		candidates = append(candidates, endpoint)
+		probeStart := time.Now()
+		if !isPortOpen(ctx, endpoint) {
+			provider.RecordFailure(endpoint)
+			config.unhealthyMembersFrequency[endpoint]++
+			if config.unhealthyMembersFrequency[endpoint] >= unhealthyMemberFrequencyThreshold {
+				if machine != nil {
+					log.Info("Marking etcd member unhealthy for removal", "endpoint", endpoint, "machine", machine.Name)
+					config.unhealthyMembersToRemove[endpoint] = machine
+				}
+			}
+			continue
+		}
+		provider.RecordSuccess(endpoint, time.Since(probeStart))
+
+		delete(config.unhealthyMembersFrequency, endpoint)
+
+		if mismatch, err := r.checkClusterIDMismatch(ctx, etcdadmCluster, endpoint); err != nil {
+			log.Error(err, "Error checking etcd cluster ID", "endpoint", endpoint)
+		} else if mismatch {
+			// A cluster ID mismatch means this endpoint's data directory no longer belongs to our
+			// cluster (e.g. a rejoined-but-reset machine) - don't wait for it to accumulate
+			// unhealthyMemberFrequencyThreshold failures, it will never be healthy for us again.
+			log.Info("etcd cluster ID mismatch detected, queuing member for removal", "endpoint", endpoint)
+			clusterIDMismatchSeen = true
+			conditions.MarkFalse(etcdadmCluster, etcdv1.EtcdClusterIDConsistentCondition, etcdv1.EtcdClusterIDMismatchReason, clusterv1.ConditionSeverityError, "endpoint %s reported an unexpected etcd cluster ID", endpoint)
+			if machine != nil {
+				config.unhealthyMembersToRemove[endpoint] = machine
+			}
+		} else if !clusterIDMismatchSeen {
+			conditions.MarkTrue(etcdadmCluster, etcdv1.EtcdClusterIDConsistentCondition)
+		}
+	}
+
+	if len(candidates) > 0 {
+		if err := r.updateHealthyEndpoints(ctx, etcdadmCluster, candidates); err != nil {
+			log.Error(err, "Error updating healthy etcd endpoints")
+		}
+	}
+
+	switch {
+	case config.learnerEndpoint != "":
+		if err := r.reconcileLearnerPromotion(ctx, etcdadmCluster, &config); err != nil {
+			log.Error(err, "Error reconciling learner promotion", "learnerEndpoint", config.learnerEndpoint)
+		}
+	case config.learnerMachineName != "":
+		// A learner Machine was created but hasn't reported an address yet: resolveLearnerEndpoint
+		// above will pick it up on a later pass. Scaling up again would trip ErrTooManyLearners.
+		log.Info("Waiting for learner Machine to report an address", "machine", config.learnerMachineName)
+	default:
+		if err := r.reconcileScaleUp(ctx, cluster, etcdadmCluster, &config); err != nil {
+			log.Error(err, "Error reconciling etcd scale-up")
+		}
+	}
+
+	etcdadmClusterMapper[etcdadmCluster.UID] = config
+	return nil
+}
+
+// reconcileLearnerPromotion polls the leader for the in-flight learner's status and, once it has
+// caught up within maxLearnerRaftIndexLag of the leader's raft index, calls MemberPromote. Each pass
+// makes at most one MemberPromote call; a failure (e.g. etcd's ErrLearnerNotReady for a premature
+// promotion) is retried on the next pass, with HealthCheckInterval as the backoff. After
+// learnerPromotionMaxAttempts failed calls the learner is no longer tracked and
+// EtcdLearnerPromotingCondition is set false with EtcdLearnerPromotionFailedReason.
+func (r *EtcdadmClusterReconciler) reconcileLearnerPromotion(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster, config *etcdadmClusterMemberHealthConfig) error {
+	cli, err := r.etcdClientForCluster(ctx, etcdadmCluster)
+	if err != nil {
+		return fmt.Errorf("creating etcd client to check learner status: %w", err)
+	}
+
+	members, err := cli.MemberList(ctx)
+	if err != nil {
+		return fmt.Errorf("listing etcd members: %w", err)
+	}
+
+	var learner *etcd.Member
+	for _, m := range members {
+		if m.IsLearner {
+			learner = m
+			break
+		}
+	}
+	if learner == nil {
+		// The learner is no longer reported as a learner: either it was already promoted out of
+		// band, or it was removed. Either way there is nothing left to promote.
+		removeFromLearnerMembers(etcdadmCluster, config.learnerEndpoint)
+		config.learnerMachineName = ""
+		config.learnerEndpoint = ""
+		config.learnerPromotionAttempts = 0
+		return nil
+	}
+
+	probeEndpoint, ok := r.nextWatchEndpoint(etcdadmCluster)
+	if !ok {
+		return fmt.Errorf("no healthy endpoint available to identify the raft leader")
+	}
+	probeStatus, err := cli.Status(ctx, probeEndpoint)
+	if err != nil {
+		return fmt.Errorf("getting etcd status for %s: %w", probeEndpoint, err)
+	}
+
+	// probeStatus.Leader is the member ID the cluster itself reports as the current raft leader -
+	// every member agrees on this regardless of which one answered - so it's cross-referenced
+	// against members to find the leader's own endpoint, rather than trusting probeEndpoint to
+	// happen to be the leader.
+	leaderMember := memberByID(members, probeStatus.Leader)
+	if leaderMember == nil || len(leaderMember.ClientURLs) == 0 {
+		return fmt.Errorf("could not resolve raft leader member %d from the member list", probeStatus.Leader)
+	}
+
+	leaderStatus, err := cli.Status(ctx, leaderMember.ClientURLs[0])
+	if err != nil {
+		return fmt.Errorf("getting etcd status for leader: %w", err)
+	}
+
+	learnerStatus, err := cli.Status(ctx, config.learnerEndpoint)
+	if err != nil {
+		return fmt.Errorf("getting etcd status for learner: %w", err)
+	}
+
+	lag := int64(leaderStatus.RaftIndex) - int64(learnerStatus.RaftIndex)
+	if lag < 0 {
+		lag = 0
+	}
+	if lag > maxLearnerRaftIndexLag {
+		conditions.MarkFalse(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition, etcdv1.EtcdLearnerNotReadyReason, clusterv1.ConditionSeverityInfo, "learner %d is %d raft entries behind the leader", learner.ID, lag)
+		return nil
+	}
+
+	if err := cli.MemberPromote(ctx, learner.ID); err != nil {
+		config.learnerPromotionAttempts++
+		if config.learnerPromotionAttempts >= learnerPromotionMaxAttempts {
+			conditions.MarkFalse(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition, etcdv1.EtcdLearnerPromotionFailedReason, clusterv1.ConditionSeverityWarning, "%v", err)
+			attempts := config.learnerPromotionAttempts
+			config.learnerMachineName = ""
+			config.learnerEndpoint = ""
+			config.learnerPromotionAttempts = 0
+			return fmt.Errorf("promoting learner %d after %d attempts: %w", learner.ID, attempts, err)
+		}
+		return fmt.Errorf("learner %d not ready for promotion, will retry on next health check pass: %w", learner.ID, err)
+	}
+
+	conditions.MarkTrue(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition)
+	removeFromLearnerMembers(etcdadmCluster, config.learnerEndpoint)
+	config.learnerMachineName = ""
+	config.learnerEndpoint = ""
+	config.learnerPromotionAttempts = 0
+	return nil
+}
+
+// memberByID returns the member in members with the given id, or nil if none match.
+func memberByID(members []*etcd.Member, id uint64) *etcd.Member {
+	for _, m := range members {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+func removeFromLearnerMembers(etcdadmCluster *etcdv1.EtcdadmCluster, endpoints ...string) {
+	remaining := etcdadmCluster.Status.LearnerMembers[:0]
+	for _, existing := range etcdadmCluster.Status.LearnerMembers {
+		keep := true
+		for _, endpoint := range endpoints {
+			if existing == endpoint {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			remaining = append(remaining, existing)
+		}
+	}
+	etcdadmCluster.Status.LearnerMembers = remaining
+}
+
+// checkClusterIDMismatch fetches endpoint's etcd cluster ID and compares it against
+// etcdadmCluster.Status.ClusterID. The first successful health check of any endpoint persists the
+// baseline; afterwards it is only ever overwritten if EtcdClusterIDOverrideAnnotation is present,
+// so a single rejoined-but-reset machine can't flip the baseline and mask itself as the mismatch.
+func (r *EtcdadmClusterReconciler) checkClusterIDMismatch(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster, endpoint string) (bool, error) {
+	cli, err := r.etcdClientForCluster(ctx, etcdadmCluster)
+	if err != nil {
+		return false, fmt.Errorf("creating etcd client to check cluster ID for endpoint %s: %w", endpoint, err)
+	}
+
+	status, err := cli.Status(ctx, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("getting etcd status for endpoint %s: %w", endpoint, err)
+	}
+	observed := fmt.Sprintf("%x", status.ClusterID)
+
+	if etcdadmCluster.Status.ClusterID == "" {
+		etcdadmCluster.Status.ClusterID = observed
+		return false, nil
+	}
+
+	if observed == etcdadmCluster.Status.ClusterID {
+		return false, nil
+	}
+
+	if etcdadmCluster.Annotations[etcdv1.EtcdClusterIDOverrideAnnotation] == "true" {
+		etcdadmCluster.Status.ClusterID = observed
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"github.com/go-logr/zapr"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zaptest"
+)
+
+func newClusterIDCheckFixtures(t *testing.T) (*EtcdadmClusterReconciler, *etcdv1.EtcdadmCluster, *fakeEtcdClient) {
+	t.Helper()
+	fakeClient := &fakeEtcdClient{statusByEndpt: map[string]*etcd.Status{}}
+
+	r := &EtcdadmClusterReconciler{Log: zapr.NewLogger(zaptest.NewLogger(t))}
+	r.newEtcdClient = func(_ []string) (etcd.Client, error) { return fakeClient, nil }
+
+	cluster := newClusterWithExternalEtcd()
+	etcdadmCluster := newEtcdadmCluster(cluster)
+	etcdadmCluster.Status.Endpoints = fakeLeaderEndpoint
+
+	return r, etcdadmCluster, fakeClient
+}
+
+func TestCheckClusterIDMismatchPersistsBaselineOnFirstCheck(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newClusterIDCheckFixtures(t)
+	fakeClient.statusByEndpt[fakeLeaderEndpoint] = &etcd.Status{ClusterID: 0xabc}
+
+	mismatch, err := r.checkClusterIDMismatch(context.Background(), etcdadmCluster, fakeLeaderEndpoint)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatch).To(BeFalse())
+	g.Expect(etcdadmCluster.Status.ClusterID).To(Equal("abc"))
+}
+
+func TestCheckClusterIDMismatchDetectsDivergence(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newClusterIDCheckFixtures(t)
+	etcdadmCluster.Status.ClusterID = "abc"
+	fakeClient.statusByEndpt[fakeLearnerEndpoint] = &etcd.Status{ClusterID: 0xdef}
+
+	mismatch, err := r.checkClusterIDMismatch(context.Background(), etcdadmCluster, fakeLearnerEndpoint)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatch).To(BeTrue())
+	g.Expect(etcdadmCluster.Status.ClusterID).To(Equal("abc"), "the persisted baseline should not move just because one endpoint disagrees")
+}
+
+func TestCheckClusterIDMismatchAllowsOverrideAnnotation(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newClusterIDCheckFixtures(t)
+	etcdadmCluster.Status.ClusterID = "abc"
+	etcdadmCluster.Annotations = map[string]string{etcdv1.EtcdClusterIDOverrideAnnotation: "true"}
+	fakeClient.statusByEndpt[fakeLeaderEndpoint] = &etcd.Status{ClusterID: 0xdef}
+
+	mismatch, err := r.checkClusterIDMismatch(context.Background(), etcdadmCluster, fakeLeaderEndpoint)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatch).To(BeFalse())
+	g.Expect(etcdadmCluster.Status.ClusterID).To(Equal("def"))
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"github.com/go-logr/zapr"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zaptest"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// fakeEtcdClient is a minimal etcd.Client double driven entirely by the fields below, letting
+// tests script the leader/learner raft indexes, which member ID Status reports as the leader, and
+// the MemberPromote outcome without a real etcd.
+type fakeEtcdClient struct {
+	statusByEndpt map[string]*etcd.Status
+	promoteErr    error
+	promoteCalls  int
+}
+
+func (f *fakeEtcdClient) MemberList(_ context.Context) ([]*etcd.Member, error) {
+	return []*etcd.Member{
+		{ID: 1, ClientURLs: []string{fakeLeaderEndpoint}},
+		{ID: 2, ClientURLs: []string{fakeLearnerEndpoint}, IsLearner: true},
+	}, nil
+}
+
+func (f *fakeEtcdClient) MemberPromote(_ context.Context, _ uint64) error {
+	f.promoteCalls++
+	return f.promoteErr
+}
+
+func (f *fakeEtcdClient) Status(_ context.Context, endpoint string) (*etcd.Status, error) {
+	s, ok := f.statusByEndpt[endpoint]
+	if !ok {
+		return nil, fmt.Errorf("no fake status configured for endpoint %s", endpoint)
+	}
+	return s, nil
+}
+
+func (f *fakeEtcdClient) SetEndpoints(_ ...string) {}
+
+func (f *fakeEtcdClient) Watch(_ context.Context, _ string) <-chan etcd.WatchEvent {
+	ch := make(chan etcd.WatchEvent)
+	close(ch)
+	return ch
+}
+
+func (f *fakeEtcdClient) Close() error { return nil }
+
+const (
+	fakeLeaderEndpoint  = "10.0.0.1:2379"
+	fakeLearnerEndpoint = "10.0.0.2:2379"
+)
+
+// newLearnerPromotionFixtures returns a reconciler wired to a fake etcd client and an EtcdadmCluster
+// whose Status.Endpoints dials fakeLeaderEndpoint.
+func newLearnerPromotionFixtures(t *testing.T) (*EtcdadmClusterReconciler, *etcdv1.EtcdadmCluster, *fakeEtcdClient) {
+	t.Helper()
+	fakeClient := &fakeEtcdClient{}
+
+	r := &EtcdadmClusterReconciler{
+		Log: zapr.NewLogger(zaptest.NewLogger(t)),
+	}
+	r.newEtcdClient = func(_ []string) (etcd.Client, error) { return fakeClient, nil }
+
+	cluster := newClusterWithExternalEtcd()
+	etcdadmCluster := newEtcdadmCluster(cluster)
+	etcdadmCluster.Status.Endpoints = fakeLeaderEndpoint
+
+	return r, etcdadmCluster, fakeClient
+}
+
+func TestReconcileLearnerPromotionSucceedsOnceCaughtUp(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newLearnerPromotionFixtures(t)
+	fakeClient.statusByEndpt = map[string]*etcd.Status{
+		fakeLeaderEndpoint:  {Leader: 1, RaftIndex: 100},
+		fakeLearnerEndpoint: {Leader: 1, RaftIndex: 100},
+	}
+	config := &etcdadmClusterMemberHealthConfig{learnerEndpoint: fakeLearnerEndpoint}
+
+	err := r.reconcileLearnerPromotion(context.Background(), etcdadmCluster, config)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(fakeClient.promoteCalls).To(Equal(1))
+	g.Expect(config.learnerEndpoint).To(BeEmpty())
+	g.Expect(conditions.IsTrue(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition)).To(BeTrue())
+}
+
+func TestReconcileLearnerPromotionWaitsWhenLagTooHigh(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newLearnerPromotionFixtures(t)
+	fakeClient.statusByEndpt = map[string]*etcd.Status{
+		fakeLeaderEndpoint:  {Leader: 1, RaftIndex: 10000},
+		fakeLearnerEndpoint: {Leader: 1, RaftIndex: 0},
+	}
+	config := &etcdadmClusterMemberHealthConfig{learnerEndpoint: fakeLearnerEndpoint}
+
+	err := r.reconcileLearnerPromotion(context.Background(), etcdadmCluster, config)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(fakeClient.promoteCalls).To(Equal(0))
+	g.Expect(config.learnerEndpoint).To(Equal(fakeLearnerEndpoint), "learner should remain tracked until it catches up")
+	g.Expect(conditions.IsFalse(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition)).To(BeTrue())
+}
+
+func TestReconcileLearnerPromotionRollsBackAfterMaxAttempts(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newLearnerPromotionFixtures(t)
+	fakeClient.statusByEndpt = map[string]*etcd.Status{
+		fakeLeaderEndpoint:  {Leader: 1, RaftIndex: 100},
+		fakeLearnerEndpoint: {Leader: 1, RaftIndex: 100},
+	}
+	fakeClient.promoteErr = fmt.Errorf("etcdserver: can't promote a learner that is not ready")
+	config := &etcdadmClusterMemberHealthConfig{learnerEndpoint: fakeLearnerEndpoint}
+
+	for i := 0; i < learnerPromotionMaxAttempts; i++ {
+		_ = r.reconcileLearnerPromotion(context.Background(), etcdadmCluster, config)
+	}
+
+	g.Expect(config.learnerEndpoint).To(BeEmpty(), "a learner stuck past the retry budget should be dropped instead of blocking future health checks")
+	g.Expect(fakeClient.promoteCalls).To(Equal(learnerPromotionMaxAttempts))
+	g.Expect(conditions.IsFalse(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition)).To(BeTrue())
+}
@@ -21,22 +21,21 @@ import (
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/collections"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/fake"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 func TestStartHealthCheckLoopPaused(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
 	core, recordedLogs := observer.New(zapcore.InfoLevel)
 	logger := zapr.NewLogger(zap.New(core))
 
 	cluster := newClusterWithExternalEtcd()
 	etcdadmCluster := newEtcdadmCluster(cluster, withPausedAnnotation)
-	fakeClient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(etcdadmCluster).Build()
+	g.Expect(testEnv.CreateAndWait(ctx, etcdadmCluster)).To(Succeed())
+	defer func() { _ = testEnv.Cleanup(ctx, etcdadmCluster) }()
 
 	r := &EtcdadmClusterReconciler{
-		Client:              fakeClient,
+		Client:              testEnv.Client,
 		Log:                 logger,
 		HealthCheckInterval: time.Second, // override the healthcheck interval to 1 second
 	}
@@ -55,7 +54,7 @@ func TestStartHealthCheckLoopPaused(t *testing.T) {
 		}),
 	}
 
-	r.startHealthCheckLoop(context.Background(), done)
+	r.startHealthCheckLoop(ctx, done)
 
 	g.Expect(recordedLogs.All()).To(Not(BeEmpty()))
 	g.Expect(recordedLogs.All()[recordedLogs.Len()-1].Message).To(Equal("HealthCheck paused for EtcdadmCluster, skipping"))
@@ -69,19 +68,22 @@ func (fn RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
 
 func TestStartHealthCheckLoop(t *testing.T) {
 	g := NewWithT(t)
+	ctx := context.Background()
 	core, recordedLogs := observer.New(zapcore.InfoLevel)
 	logger := zapr.NewLogger(zap.New(core))
 
 	cluster := newClusterWithExternalEtcd()
 	etcdadmCluster := newEtcdadmCluster(cluster)
+	g.Expect(testEnv.CreateAndWait(ctx, etcdadmCluster)).To(Succeed())
+	defer func() { _ = testEnv.Cleanup(ctx, etcdadmCluster) }()
+
 	etcdadmCluster.Status.CreationComplete = true
-	fakeClient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(etcdadmCluster).Build()
+	g.Expect(testEnv.Client.Status().Update(ctx, etcdadmCluster)).To(Succeed())
 
-	// fakeClient.Create()
 	r := &EtcdadmClusterReconciler{
-		Client:              fakeClient,
+		Client:              testEnv.Client,
 		Log:                 logger,
-		HealthCheckInterval: 1, // override the healthcheck interval to 1 second
+		HealthCheckInterval: time.Second, // override the healthcheck interval to 1 second
 	}
 	r.SetIsPortOpen(func(_ context.Context, _ string) bool { return true })
 
@@ -93,7 +95,7 @@ func TestStartHealthCheckLoop(t *testing.T) {
 		close(done)
 	}()
 
-	r.startHealthCheckLoop(context.Background(), done)
+	r.startHealthCheckLoop(ctx, done)
 
 	g.Expect(recordedLogs.All()).To(Not(BeEmpty()))
 	g.Expect(recordedLogs.All()[recordedLogs.Len()-1].Message).To(Equal("HealthCheck paused for EtcdadmCluster, skipping"))
@@ -101,15 +103,24 @@ func TestStartHealthCheckLoop(t *testing.T) {
 
 // This test verifies that the periodicEtcdMembersHealthCheck does not panic when a Machine corresponding to an ETCD endpoint doesn not exist.
 func TestReconcilePerodicHealthCheckEnsureNoPanic(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
 	cluster := newClusterWithExternalEtcd()
 	etcdadmCluster := newEtcdadmCluster(cluster)
-	ctx := context.Background()
+
+	g.Expect(testEnv.CreateAndWait(ctx, infraTemplate.DeepCopy())).To(Succeed())
+	g.Expect(testEnv.CreateAndWait(ctx, cluster)).To(Succeed())
+	g.Expect(testEnv.CreateAndWait(ctx, etcdadmCluster)).To(Succeed())
+	defer func() { _ = testEnv.Cleanup(ctx, cluster, etcdadmCluster) }()
 
 	ownedMachine := newEtcdMachineWithEndpoint(etcdadmCluster, cluster)
+	g.Expect(testEnv.CreateAndWait(ctx, ownedMachine)).To(Succeed())
+	defer func() { _ = testEnv.Cleanup(ctx, ownedMachine) }()
+
 	ownedMachines := make(collections.Machines, 1)
 	ownedMachines.Insert(ownedMachine)
 
-	etcdadmCluster.UID = "test-uid"
 	etcdadmClusterMapper := make(map[types.UID]etcdadmClusterMemberHealthConfig, 1)
 
 	ownedMachineEndpoint := ownedMachine.Status.Addresses[0].Address
@@ -127,18 +138,9 @@ func TestReconcilePerodicHealthCheckEnsureNoPanic(t *testing.T) {
 		ownedMachines:             ownedMachines,
 	}
 
-	objects := []client.Object{
-		cluster,
-		etcdadmCluster,
-		infraTemplate.DeepCopy(),
-		ownedMachine,
-	}
-	fakeClient := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(objects...).Build()
-
 	r := &EtcdadmClusterReconciler{
-		Client:         fakeClient,
-		uncachedClient: fakeClient,
-		Log:            log.Log,
+		Client: testEnv.Client,
+		Log:    testEnv.GetLogger(),
 	}
 
 	// This ensures that the test did not panic.
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	etcdbootstrapv1 "github.com/aws/etcdadm-bootstrap-provider/api/v1beta1"
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// defaultReplicas is the number of etcd members EtcdadmClusterSpec.Replicas defaults to when unset,
+// matching the documented default on the field.
+const defaultReplicas = 3
+
+// ErrTooManyLearners mirrors etcd's rpctypes.ErrTooManyLearners: etcd only allows one learner in
+// the cluster at a time, so the reconciler must not add a second one while a promotion is pending.
+var ErrTooManyLearners = fmt.Errorf("a learner member is already in flight for this EtcdadmCluster")
+
+// reconcileScaleUp adds a learner Machine when etcdadmCluster has fewer known members than
+// EtcdadmClusterSpec.Replicas. periodicEtcdMembersHealthCheck does not call this while a learner is
+// tracked, and addLearnerMachine also refuses to add a second learner while one is already in
+// flight; if it reports ErrTooManyLearners, that's surfaced on
+// EtcdLearnerPromotingCondition instead of treated as an error, since reconcileLearnerPromotion is
+// already driving the in-flight learner the rest of the way to a voting member.
+func (r *EtcdadmClusterReconciler) reconcileScaleUp(ctx context.Context, cluster *clusterv1.Cluster, etcdadmCluster *etcdv1.EtcdadmCluster, config *etcdadmClusterMemberHealthConfig) error {
+	desired := int32(defaultReplicas)
+	if etcdadmCluster.Spec.Replicas != nil {
+		desired = *etcdadmCluster.Spec.Replicas
+	}
+	current := int32(len(config.endpointToMachineMapper))
+	if current >= desired {
+		return nil
+	}
+
+	if err := r.addLearnerMachine(ctx, cluster, etcdadmCluster, config); err != nil {
+		if errors.Is(err, ErrTooManyLearners) {
+			conditions.MarkFalse(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition, etcdv1.EtcdTooManyLearnersReason, clusterv1.ConditionSeverityInfo, "skipping scale-up: a learner is already in flight")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// addLearnerMachine creates the next Machine for a scale-up, setting CloudInitConfig.Learner on
+// its EtcdadmConfig so etcdadm joins it with `etcdadm join --learner ...` rather than as a voting
+// member. The Machine is tracked in config.learnerMachineName until discoverEtcdMembers reports its
+// real address (config.learnerEndpoint and EtcdadmCluster.Status.LearnerMembers are only populated
+// once that address is known - the Machine's infrastructure hasn't provisioned it yet at creation
+// time), and periodicEtcdMembersHealthCheck promotes it from there.
+func (r *EtcdadmClusterReconciler) addLearnerMachine(ctx context.Context, cluster *clusterv1.Cluster, etcdadmCluster *etcdv1.EtcdadmCluster, config *etcdadmClusterMemberHealthConfig) error {
+	if config.learnerMachineName != "" {
+		return ErrTooManyLearners
+	}
+
+	ownerRef := *metav1.NewControllerRef(etcdadmCluster, etcdv1.GroupVersion.WithKind("EtcdadmCluster"))
+
+	configSpec := etcdadmCluster.Spec.EtcdadmConfigSpec
+	cloudInitConfig := etcdbootstrapv1.CloudInitConfig{}
+	if configSpec.CloudInitConfig != nil {
+		cloudInitConfig = *configSpec.CloudInitConfig
+	}
+	cloudInitConfig.Learner = true
+	configSpec.CloudInitConfig = &cloudInitConfig
+
+	bootstrapConfig := &etcdbootstrapv1.EtcdadmConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    fmt.Sprintf("%s-learner-", etcdadmCluster.Name),
+			Namespace:       etcdadmCluster.Namespace,
+			OwnerReferences: []metav1.OwnerReference{ownerRef},
+		},
+		Spec: configSpec,
+	}
+	if err := r.Client.Create(ctx, bootstrapConfig); err != nil {
+		return fmt.Errorf("creating learner EtcdadmConfig: %w", err)
+	}
+
+	infraRef, err := external.CloneTemplate(ctx, &external.CloneTemplateInput{
+		Client:      r.Client,
+		TemplateRef: &etcdadmCluster.Spec.InfrastructureTemplate,
+		Namespace:   etcdadmCluster.Namespace,
+		OwnerRef:    &ownerRef,
+		ClusterName: cluster.Name,
+		Labels:      map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+	})
+	if err != nil {
+		return fmt.Errorf("cloning infrastructure template for learner Machine: %w", err)
+	}
+
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    fmt.Sprintf("%s-learner-", etcdadmCluster.Name),
+			Namespace:       etcdadmCluster.Namespace,
+			Labels:          map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			OwnerReferences: []metav1.OwnerReference{ownerRef},
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName:       cluster.Name,
+			InfrastructureRef: *infraRef,
+			Bootstrap: clusterv1.Bootstrap{
+				ConfigRef: &corev1.ObjectReference{
+					APIVersion: etcdbootstrapv1.GroupVersion.String(),
+					Kind:       "EtcdadmConfig",
+					Name:       bootstrapConfig.Name,
+					Namespace:  bootstrapConfig.Namespace,
+				},
+			},
+		},
+	}
+	if err := r.Client.Create(ctx, machine); err != nil {
+		return fmt.Errorf("creating learner Machine: %w", err)
+	}
+
+	config.learnerMachineName = machine.Name
+	config.learnerPromotionAttempts = 0
+	conditions.MarkFalse(etcdadmCluster, etcdv1.EtcdLearnerPromotingCondition, etcdv1.EtcdLearnerNotReadyReason, clusterv1.ConditionSeverityInfo, "waiting for learner Machine %s to report an address", machine.Name)
+
+	return nil
+}
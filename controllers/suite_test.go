@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/internal/envtest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// testEnv is a single envtest.Environment shared by every test in this package, mirroring the CAPI
+// pattern of one real etcd+apiserver per test binary rather than one per test.
+var testEnv *envtest.Environment
+
+func TestMain(m *testing.M) {
+	var err error
+	testEnv, err = envtest.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest environment: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := testEnv.StartManager(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest manager: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	if err := testEnv.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to stop envtest environment: %v\n", err)
+	}
+
+	os.Exit(code)
+}
+
+const (
+	testNamespace          = "test-namespace"
+	testClusterName        = "test-cluster"
+	testEtcdadmClusterName = "test-etcdadm-cluster"
+)
+
+var infraTemplate = &unstructured.Unstructured{
+	Object: map[string]interface{}{
+		"kind":       "GenericInfrastructureMachineTemplate",
+		"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta1",
+		"metadata": map[string]interface{}{
+			"name":      "infra-template",
+			"namespace": testNamespace,
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{},
+			},
+		},
+	},
+}
+
+func newClusterWithExternalEtcd() *clusterv1.Cluster {
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testClusterName,
+			Namespace: testNamespace,
+		},
+		Spec: clusterv1.ClusterSpec{
+			ManagedExternalEtcdRef: &corev1.ObjectReference{
+				Kind:       "EtcdadmCluster",
+				APIVersion: etcdv1.GroupVersion.String(),
+				Name:       testEtcdadmClusterName,
+				Namespace:  testNamespace,
+			},
+		},
+	}
+}
+
+type etcdadmClusterOption func(*etcdv1.EtcdadmCluster)
+
+func withPausedAnnotation(e *etcdv1.EtcdadmCluster) {
+	if e.Annotations == nil {
+		e.Annotations = map[string]string{}
+	}
+	e.Annotations[clusterv1.PausedAnnotation] = "true"
+}
+
+func newEtcdadmCluster(cluster *clusterv1.Cluster, opts ...etcdadmClusterOption) *etcdv1.EtcdadmCluster {
+	e := (*etcdv1.EtcdadmCluster)(getNewEtcdadmCluster(cluster))
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+func newEtcdMachine(etcdadmCluster *etcdv1.EtcdadmCluster, cluster *clusterv1.Cluster) *clusterv1.Machine {
+	return &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    testNamespace,
+			GenerateName: fmt.Sprintf("%s-", etcdadmCluster.Name),
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabel: cluster.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					Kind:       "EtcdadmCluster",
+					APIVersion: etcdv1.GroupVersion.String(),
+					Name:       etcdadmCluster.Name,
+					UID:        etcdadmCluster.UID,
+				},
+			},
+		},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: cluster.Name,
+		},
+	}
+}
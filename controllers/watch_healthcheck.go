@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultWatchLoopUnhealthyTimeout is how long the watch health surveillance loop waits
+	// without a watch event or a successful detectHealthyInterval probe before rotating off the
+	// current endpoint, when WatchLoopUnhealthyTimeout is left unset.
+	defaultWatchLoopUnhealthyTimeout = 60 * time.Second
+
+	// defaultDetectHealthyInterval is the period of the secondary probe the watch loop runs
+	// against its current endpoint, when DetectHealthyInterval is left unset.
+	defaultDetectHealthyInterval = 10 * time.Second
+
+	// etcdClusterInfoKey is the well-known key etcdadm writes cluster metadata to. Watching it is
+	// a cheap way to notice a member that has gone silent without round tripping through the full
+	// member/status RPCs on every tick.
+	etcdClusterInfoKey = "/registry/etcdadm/cluster-info"
+)
+
+// ensureWatchLoop starts the watch health surveillance loop for etcdadmCluster if one isn't
+// already running, so startHealthCheckLoop can call this unconditionally on every tick without
+// spawning duplicate goroutines per cluster.
+func (r *EtcdadmClusterReconciler) ensureWatchLoop(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster) {
+	r.watchLoopsMu.Lock()
+	defer r.watchLoopsMu.Unlock()
+
+	if r.watchLoopCancels == nil {
+		r.watchLoopCancels = make(map[types.UID]context.CancelFunc)
+	}
+	if _, running := r.watchLoopCancels[etcdadmCluster.UID]; running {
+		return
+	}
+
+	loopCtx, cancel := context.WithCancel(ctx)
+	r.watchLoopCancels[etcdadmCluster.UID] = cancel
+
+	namespace, name, uid := etcdadmCluster.Namespace, etcdadmCluster.Name, etcdadmCluster.UID
+	go func() {
+		defer func() {
+			r.watchLoopsMu.Lock()
+			delete(r.watchLoopCancels, uid)
+			r.watchLoopsMu.Unlock()
+		}()
+		r.runWatchLoop(loopCtx, namespace, name)
+	}()
+}
+
+// stopWatchLoop cancels the running watch health surveillance loop for uid, if one is running. The
+// loop removes itself from watchLoopCancels on exit, so this only needs to signal it to stop.
+func (r *EtcdadmClusterReconciler) stopWatchLoop(uid types.UID) {
+	r.watchLoopsMu.Lock()
+	cancel, ok := r.watchLoopCancels[uid]
+	r.watchLoopsMu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// runWatchLoop repeatedly picks the current best endpoint for namespace/name and watches it until
+// it goes unhealthy, then rotates to the next one, until ctx is done.
+func (r *EtcdadmClusterReconciler) runWatchLoop(ctx context.Context, namespace, name string) {
+	log := r.Log.WithValues("etcdadmCluster", name, "namespace", namespace)
+	detectInterval := r.DetectHealthyInterval
+	if detectInterval <= 0 {
+		detectInterval = defaultDetectHealthyInterval
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		var etcdadmCluster etcdv1.EtcdadmCluster
+		if err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &etcdadmCluster); err != nil {
+			log.Error(err, "Error refetching EtcdadmCluster for watch loop")
+			if !sleepOrDone(ctx, detectInterval) {
+				return
+			}
+			continue
+		}
+
+		endpoint, ok := r.nextWatchEndpoint(&etcdadmCluster)
+		if !ok {
+			if !sleepOrDone(ctx, detectInterval) {
+				return
+			}
+			continue
+		}
+
+		if !r.watchEndpointUntilUnhealthy(ctx, &etcdadmCluster, endpoint, detectInterval) {
+			return
+		}
+	}
+}
+
+// nextWatchEndpoint returns the best-ranked healthy endpoint from etcdadmCluster.Status.Endpoints,
+// or false if there is nothing to watch yet.
+func (r *EtcdadmClusterReconciler) nextWatchEndpoint(etcdadmCluster *etcdv1.EtcdadmCluster) (string, bool) {
+	if etcdadmCluster.Status.Endpoints == "" {
+		return "", false
+	}
+	candidates := strings.Split(etcdadmCluster.Status.Endpoints, ",")
+	healthy := r.endpointsProviderFor(etcdadmCluster).HealthyEndpoints(candidates)
+	if len(healthy) == 0 {
+		return "", false
+	}
+	return healthy[0], true
+}
+
+// watchEndpointUntilUnhealthy watches endpoint's cluster-info key until the watch is canceled or
+// WatchLoopUnhealthyTimeout elapses without a watch event or a successful detectHealthyInterval
+// probe - catching a silent TCP blackhole that IsPortOpen alone would miss, since the socket
+// accepts but the server never sends anything back. It returns false only if ctx was canceled out
+// from under it, so runWatchLoop knows to stop rather than pick a new endpoint.
+func (r *EtcdadmClusterReconciler) watchEndpointUntilUnhealthy(ctx context.Context, etcdadmCluster *etcdv1.EtcdadmCluster, endpoint string, detectInterval time.Duration) bool {
+	log := r.Log.WithValues("etcdadmCluster", etcdadmCluster.Name, "namespace", etcdadmCluster.Namespace, "endpoint", endpoint)
+
+	unhealthyTimeout := r.WatchLoopUnhealthyTimeout
+	if unhealthyTimeout <= 0 {
+		unhealthyTimeout = defaultWatchLoopUnhealthyTimeout
+	}
+
+	cli, err := r.etcdClientForCluster(ctx, etcdadmCluster)
+	if err != nil {
+		log.Error(err, "Error getting etcd client for watch loop")
+		return ctx.Err() == nil
+	}
+
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+	events := cli.Watch(watchCtx, etcdClusterInfoKey)
+
+	provider := r.endpointsProviderFor(etcdadmCluster)
+	isPortOpen := r.isPortOpenOrDefault()
+
+	detectTicker := time.NewTicker(detectInterval)
+	defer detectTicker.Stop()
+	unhealthyTimer := time.NewTimer(unhealthyTimeout)
+	defer unhealthyTimer.Stop()
+
+	resetUnhealthyTimer := func() {
+		if !unhealthyTimer.Stop() {
+			<-unhealthyTimer.C
+		}
+		unhealthyTimer.Reset(unhealthyTimeout)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case evt, open := <-events:
+			if !open || evt.Canceled {
+				log.Info("etcd watch canceled, rotating to next healthy endpoint", "err", evt.Err)
+				watchReconnectTotal.WithLabelValues(etcdadmCluster.Name).Inc()
+				provider.RecordFailure(endpoint)
+				return true
+			}
+			resetUnhealthyTimer()
+
+		case <-detectTicker.C:
+			start := time.Now()
+			if isPortOpen(ctx, endpoint) {
+				provider.RecordSuccess(endpoint, time.Since(start))
+				resetUnhealthyTimer()
+			} else {
+				provider.RecordFailure(endpoint)
+			}
+
+		case <-unhealthyTimer.C:
+			log.Info("no watch event or healthy probe within WatchLoopUnhealthyTimeout, rotating to next healthy endpoint")
+			watchReconnectTotal.WithLabelValues(etcdadmCluster.Name).Inc()
+			provider.RecordFailure(endpoint)
+			return true
+		}
+	}
+}
+
+// sleepOrDone waits for d, returning false early if ctx is done first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
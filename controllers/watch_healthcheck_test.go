@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	"github.com/aws/etcdadm-controller/controllers/etcd"
+	"github.com/go-logr/zapr"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zaptest"
+)
+
+// watchEtcdClient is a minimal etcd.Client double whose Watch channel is driven directly by the
+// test, so it can simulate a live watch, a server-side cancellation, or a channel that never
+// delivers anything.
+type watchEtcdClient struct {
+	fakeEtcdClient
+	events chan etcd.WatchEvent
+}
+
+func (f *watchEtcdClient) Watch(_ context.Context, _ string) <-chan etcd.WatchEvent {
+	return f.events
+}
+
+func newWatchLoopFixtures(t *testing.T) (*EtcdadmClusterReconciler, *etcdv1.EtcdadmCluster, *watchEtcdClient) {
+	t.Helper()
+	fakeClient := &watchEtcdClient{events: make(chan etcd.WatchEvent)}
+
+	r := &EtcdadmClusterReconciler{
+		Client:                    testEnv.Client,
+		Log:                       zapr.NewLogger(zaptest.NewLogger(t)),
+		WatchLoopUnhealthyTimeout: 50 * time.Millisecond,
+		DetectHealthyInterval:     10 * time.Millisecond,
+	}
+	r.newEtcdClient = func(_ []string) (etcd.Client, error) { return fakeClient, nil }
+	r.SetIsPortOpen(func(_ context.Context, _ string) bool { return false })
+
+	cluster := newClusterWithExternalEtcd()
+	etcdadmCluster := newEtcdadmCluster(cluster)
+	etcdadmCluster.Status.Endpoints = fakeLeaderEndpoint
+
+	return r, etcdadmCluster, fakeClient
+}
+
+func TestWatchEndpointUntilUnhealthyRotatesOnCancellation(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, fakeClient := newWatchLoopFixtures(t)
+
+	resultCh := make(chan bool, 1)
+	go func() {
+		resultCh <- r.watchEndpointUntilUnhealthy(context.Background(), etcdadmCluster, fakeLeaderEndpoint, r.DetectHealthyInterval)
+	}()
+
+	fakeClient.events <- etcd.WatchEvent{Canceled: true}
+
+	g.Eventually(resultCh).Should(Receive(BeTrue()))
+}
+
+func TestWatchEndpointUntilUnhealthyRotatesOnTimeout(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, _ := newWatchLoopFixtures(t)
+
+	result := r.watchEndpointUntilUnhealthy(context.Background(), etcdadmCluster, fakeLeaderEndpoint, r.DetectHealthyInterval)
+
+	g.Expect(result).To(BeTrue(), "an endpoint with no watch events and a failing probe should be rotated away from once WatchLoopUnhealthyTimeout elapses")
+}
+
+func TestWatchEndpointUntilUnhealthyStopsWhenContextCanceled(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, _ := newWatchLoopFixtures(t)
+	r.WatchLoopUnhealthyTimeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resultCh := make(chan bool, 1)
+	go func() {
+		resultCh <- r.watchEndpointUntilUnhealthy(ctx, etcdadmCluster, fakeLeaderEndpoint, r.DetectHealthyInterval)
+	}()
+
+	cancel()
+
+	g.Eventually(resultCh).Should(Receive(BeFalse()))
+}
+
+func TestNextWatchEndpointReturnsFalseWithoutEndpoints(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, _ := newWatchLoopFixtures(t)
+	etcdadmCluster.Status.Endpoints = ""
+
+	_, ok := r.nextWatchEndpoint(etcdadmCluster)
+
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestEnsureWatchLoopIsIdempotentPerCluster(t *testing.T) {
+	g := NewWithT(t)
+	r, etcdadmCluster, _ := newWatchLoopFixtures(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r.ensureWatchLoop(ctx, etcdadmCluster)
+	r.ensureWatchLoop(ctx, etcdadmCluster)
+
+	g.Expect(r.watchLoopCancels).To(HaveLen(1), "a second ensureWatchLoop call for the same cluster should not start a duplicate loop")
+}
@@ -0,0 +1,152 @@
+// Package envtest boots a real etcd+kube-apiserver for this repo's controller tests, mirroring
+// the test environment used by Cluster API itself. It exists because sigs.k8s.io/controller-runtime's
+// fake client can't drive watch-based reconciles, status subresource updates, or ownerRef garbage
+// collection - all of which the health check and learner-promotion tests need to exercise.
+package envtest
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	etcdbootstrapv1 "github.com/aws/etcdadm-bootstrap-provider/api/v1beta1"
+	etcdv1 "github.com/aws/etcdadm-controller/api/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlenvtest "sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Environment wraps a running envtest.Environment and controller-runtime Manager with the scheme
+// and CRDs this repo's controllers need.
+type Environment struct {
+	manager.Manager
+	Client client.Client
+
+	env    *ctrlenvtest.Environment
+	cancel context.CancelFunc
+}
+
+// crdDirsForModules resolves the on-disk config/crd/bases directory for each given module path by
+// shelling out to `go list`, the same way sigs.k8s.io/cluster-api's own envtest test harness locates
+// the CRDs a dependency module ships rather than vendoring a copy of them into this repo. It
+// requires `go mod download` to have already populated the module cache, which `go test` does
+// automatically.
+func crdDirsForModules(modules ...string) ([]string, error) {
+	dirs := make([]string, 0, len(modules))
+	for _, module := range modules {
+		out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", module).Output()
+		if err != nil {
+			return nil, fmt.Errorf("locating module %s (run `go mod download` first): %w", module, err)
+		}
+		dirs = append(dirs, filepath.Join(strings.TrimSpace(string(out)), "config", "crd", "bases"))
+	}
+	return dirs, nil
+}
+
+// New boots a real etcd+kube-apiserver via envtest.Environment, installs the etcdadm/CAPI CRDs,
+// and returns a Manager wired to it. Callers must call StartManager before using Client against
+// anything that depends on the manager's cache (Get/List), and Stop when done.
+func New() (*Environment, error) {
+	depCRDDirs, err := crdDirsForModules("sigs.k8s.io/cluster-api", "github.com/aws/etcdadm-bootstrap-provider")
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve this repo's CRDs relative to this source file rather than the working directory, which
+	// `go test` sets to the directory of whichever package is under test.
+	_, thisFile, _, _ := goruntime.Caller(0)
+	env := &ctrlenvtest.Environment{
+		CRDDirectoryPaths: append([]string{
+			filepath.Join(filepath.Dir(thisFile), "..", "..", "config", "crd", "bases"),
+		}, depCRDDirs...),
+		ErrorIfCRDPathMissing: true,
+	}
+
+	cfg, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("starting envtest environment: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		clusterv1.AddToScheme,
+		etcdbootstrapv1.AddToScheme,
+		etcdv1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			return nil, fmt.Errorf("registering scheme: %w", err)
+		}
+	}
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("creating manager: %w", err)
+	}
+
+	return &Environment{Manager: mgr, Client: mgr.GetClient(), env: env}, nil
+}
+
+// StartManager starts the manager's cache and informers in the background and blocks until the
+// cache has synced, so tests can Get/List immediately after this returns.
+func (e *Environment) StartManager(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+
+	go func() {
+		_ = e.Manager.Start(ctx)
+	}()
+
+	if !e.Manager.GetCache().WaitForCacheSync(ctx) {
+		return fmt.Errorf("cache never synced")
+	}
+	return nil
+}
+
+// CreateAndWait creates obj through the manager's client and polls until it is readable back from
+// the manager's cache, so assertions immediately following a Create don't race the informer.
+func (e *Environment) CreateAndWait(ctx context.Context, obj client.Object) error {
+	if err := e.Client.Create(ctx, obj); err != nil {
+		return fmt.Errorf("creating %T %s: %w", obj, obj.GetName(), err)
+	}
+
+	key := client.ObjectKeyFromObject(obj)
+	return wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+		if err := e.Client.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// Cleanup deletes every object passed in, ignoring not-found errors so it's safe to call
+// unconditionally from a test's defer.
+func (e *Environment) Cleanup(ctx context.Context, objs ...client.Object) error {
+	for _, obj := range objs {
+		if err := e.Client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting %T %s: %w", obj, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// Stop cancels the manager and tears down the envtest.Environment's etcd and kube-apiserver.
+func (e *Environment) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	return e.env.Stop()
+}